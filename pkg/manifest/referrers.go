@@ -0,0 +1,111 @@
+// Copyright 2021 The Audit Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// cosign-style artifact media types, used to classify the entries of the
+// OCI 1.1 referrers response. The legacy tag-based fallback does not use
+// them; it classifies by tag suffix.
+const (
+	signatureArtifactType  = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	sbomSPDXArtifactType   = "application/spdx+json"
+	sbomCDXArtifactType    = "application/vnd.cyclonedx+json"
+	provenanceArtifactType = "application/vnd.in-toto+json"
+)
+
+// Artifacts reports which OCI referrer kinds were found for an image.
+type Artifacts struct {
+	Signed     bool
+	SBOM       bool
+	Provenance bool
+}
+
+// FetchArtifacts looks up the Cosign signature, SBOM and SLSA provenance
+// attestation referrers for imageRef. It prefers the OCI 1.1 `/referrers`
+// API and falls back to the legacy cosign tag-based scheme
+// (`sha256-<digest>.sig`/`.sbom`/`.att`) for registries that don't yet
+// implement it.
+func (r *Resolver) FetchArtifacts(imageRef string) (*Artifacts, error) {
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse image reference (%s) : %s", imageRef, err)
+	}
+
+	digestRef, ok := ref.(name.Digest)
+	if !ok {
+		info, err := r.Resolve(imageRef)
+		if err != nil {
+			return nil, err
+		}
+		digestRef, err = name.NewDigest(fmt.Sprintf("%s@%s", ref.Context().Name(), info.Digest))
+		if err != nil {
+			return nil, fmt.Errorf("unable to build digest reference for (%s) : %s", imageRef, err)
+		}
+	}
+
+	opts := []remote.Option{remote.WithAuthFromKeychain(r.keychain)}
+	if r.transport != nil {
+		opts = append(opts, remote.WithTransport(r.transport))
+	}
+
+	artifacts := &Artifacts{}
+
+	idx, err := remote.Referrers(digestRef, opts...)
+	if err == nil {
+		manifest, err := idx.IndexManifest()
+		if err == nil && len(manifest.Manifests) > 0 {
+			for _, d := range manifest.Manifests {
+				classify(string(d.ArtifactType), artifacts)
+			}
+			return artifacts, nil
+		}
+	}
+
+	// Fall back to the cosign tag-based scheme for registries without the
+	// OCI 1.1 referrers API, and for registries that implement it but
+	// returned an empty index (e.g. referrers pushed only via the legacy
+	// tag scheme).
+	digestHex := strings.TrimPrefix(digestRef.DigestStr(), "sha256:")
+	for suffix, classify := range map[string]func(*Artifacts){
+		".sig":  func(a *Artifacts) { a.Signed = true },
+		".sbom": func(a *Artifacts) { a.SBOM = true },
+		".att":  func(a *Artifacts) { a.Provenance = true },
+	} {
+		tag := digestRef.Context().Tag(fmt.Sprintf("sha256-%s%s", digestHex, suffix))
+		if _, err := remote.Head(tag, opts...); err == nil {
+			classify(artifacts)
+		}
+	}
+
+	return artifacts, nil
+}
+
+func classify(artifactType string, artifacts *Artifacts) {
+	switch {
+	case artifactType == signatureArtifactType:
+		artifacts.Signed = true
+	case artifactType == sbomSPDXArtifactType || artifactType == sbomCDXArtifactType:
+		artifacts.SBOM = true
+	case artifactType == provenanceArtifactType:
+		artifacts.Provenance = true
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2021 The Audit Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package manifest provides a native (non-shell-out) lookup of container
+// image manifests and their platform variants, backed by
+// github.com/google/go-containerregistry instead of a local docker/podman
+// binary.
+package manifest
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// defaultCacheSize bounds how many image references the resolver keeps
+// manifest data for. One entry is cheap (a handful of platform tuples), so
+// this is sized generously for large indices.
+const defaultCacheSize = 4096
+
+// Platform identifies a single manifest entry of a multi-arch image.
+type Platform struct {
+	OS           string
+	Architecture string
+	Variant      string
+	OSVersion    string
+	Digest       string
+}
+
+// Info is the resolved manifest data for an image reference: its own
+// digest plus, when it points at an index, every platform it contains.
+type Info struct {
+	Digest    string
+	Platforms []Platform
+}
+
+// Resolver resolves image references to Info, caching results by image
+// reference so that a reference already resolved is served from the cache
+// instead of being fetched again. Concurrent lookups of the same reference
+// that are in flight at once are not merged.
+type Resolver struct {
+	keychain  authn.Keychain
+	transport http.RoundTripper
+	cache     *lru.Cache[string, *Info]
+	sem       chan struct{}
+}
+
+// Option configures a Resolver.
+type Option func(*Resolver)
+
+// WithKeychain overrides the authn.Keychain used to authenticate against
+// registries. Defaults to authn.DefaultKeychain, which resolves the same
+// docker config.json credentials the docker/podman CLIs use.
+func WithKeychain(k authn.Keychain) Option {
+	return func(r *Resolver) { r.keychain = k }
+}
+
+// WithTransport overrides the http.RoundTripper used for registry requests,
+// e.g. to inject TLS config for a mirror or an insecure-registry override.
+func WithTransport(t http.RoundTripper) Option {
+	return func(r *Resolver) { r.transport = t }
+}
+
+// WithConcurrency bounds how many manifest lookups are in flight at once.
+func WithConcurrency(n int) Option {
+	return func(r *Resolver) {
+		if n <= 0 {
+			n = 1
+		}
+		r.sem = make(chan struct{}, n)
+	}
+}
+
+// NewResolver builds a Resolver ready to use. With no options it
+// authenticates via the default docker keychain and allows a single
+// in-flight lookup at a time.
+func NewResolver(opts ...Option) *Resolver {
+	cache, _ := lru.New[string, *Info](defaultCacheSize)
+	r := &Resolver{
+		keychain: authn.DefaultKeychain,
+		cache:    cache,
+		sem:      make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// DefaultResolver is the process-wide Resolver shared by the multi-arch
+// report so that bundles referencing the same image reference share its
+// cached result instead of each paying for the registry round trip.
+var DefaultResolver = NewResolver()
+
+// once guards re-configuring DefaultResolver's concurrency from flags.
+var once sync.Once
+
+// Configure applies the user-provided concurrency to DefaultResolver. It is
+// a no-op after the first call, since all bundles in a report share the
+// same resolver.
+func Configure(concurrency int) {
+	once.Do(func() {
+		if concurrency > 0 {
+			DefaultResolver.sem = make(chan struct{}, concurrency)
+		}
+	})
+}
+
+// Resolve looks up the manifest (or manifest list/index) for imageRef and
+// returns every platform it advertises. Results are cached under imageRef
+// exactly as given; it is not normalised first.
+func (r *Resolver) Resolve(imageRef string) (*Info, error) {
+	if cached, ok := r.cache.Get(imageRef); ok {
+		return cached, nil
+	}
+
+	r.sem <- struct{}{}
+	defer func() { <-r.sem }()
+
+	// Another goroutine may have populated the cache while we waited on sem.
+	if cached, ok := r.cache.Get(imageRef); ok {
+		return cached, nil
+	}
+
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse image reference (%s) : %s", imageRef, err)
+	}
+
+	opts := []remote.Option{remote.WithAuthFromKeychain(r.keychain)}
+	if r.transport != nil {
+		opts = append(opts, remote.WithTransport(r.transport))
+	}
+
+	desc, err := remote.Get(ref, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get manifest for image (%s) : %s", imageRef, err)
+	}
+
+	info := &Info{Digest: desc.Digest.String()}
+
+	if desc.MediaType.IsIndex() {
+		idx, err := desc.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read image index for (%s) : %s", imageRef, err)
+		}
+		manifest, err := idx.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read index manifest for (%s) : %s", imageRef, err)
+		}
+		for _, child := range manifest.Manifests {
+			info.Platforms = append(info.Platforms, platformFrom(child.Platform, child.Digest))
+		}
+	} else {
+		img, err := desc.Image()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read image for (%s) : %s", imageRef, err)
+		}
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read image config for (%s) : %s", imageRef, err)
+		}
+		info.Platforms = append(info.Platforms, Platform{
+			OS:           cfg.OS,
+			Architecture: cfg.Architecture,
+			Variant:      cfg.Variant,
+			OSVersion:    cfg.OSVersion,
+			Digest:       desc.Digest.String(),
+		})
+	}
+
+	r.cache.Add(imageRef, info)
+	return info, nil
+}
+
+func platformFrom(p *v1.Platform, digest v1.Hash) Platform {
+	if p == nil {
+		return Platform{Digest: digest.String()}
+	}
+	return Platform{
+		OS:           p.OS,
+		Architecture: p.Architecture,
+		Variant:      p.Variant,
+		OSVersion:    p.OSVersion,
+		Digest:       digest.String(),
+	}
+}
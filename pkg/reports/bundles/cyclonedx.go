@@ -0,0 +1,191 @@
+// Copyright 2021 The Audit Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bundles
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/operator-framework/audit/pkg/manifest"
+	log "github.com/sirupsen/logrus"
+)
+
+// cycloneDXComponent is the subset of the CycloneDX 1.5 component schema
+// this report populates: https://cyclonedx.org/docs/1.5/json/#components.
+type cycloneDXComponent struct {
+	BOMRef     string              `json:"bom-ref"`
+	Type       string              `json:"type"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	PURL       string              `json:"purl,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+type cycloneDXMetadata struct {
+	Component cycloneDXComponent `json:"component"`
+}
+
+// cycloneDXBOM is the root of a CycloneDX 1.5 JSON SBOM document.
+type cycloneDXBOM struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+// writeCycloneDX emits a CycloneDX 1.5 JSON SBOM describing the index
+// image, every bundle discovered and every install/related image they
+// reference, with one component per platform variant and a dependency
+// graph wiring index -> bundles -> images.
+func (r Report) writeCycloneDX() error {
+	indexRef := fmt.Sprintf("index:%s", r.Flags.IndexImage)
+	bom := cycloneDXBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: cycloneDXMetadata{
+			Component: cycloneDXComponent{
+				BOMRef: indexRef,
+				Type:   "container",
+				Name:   r.Flags.IndexImage,
+				PURL:   purlFor(r.Flags.IndexImage, ""),
+			},
+		},
+	}
+
+	indexDeps := cycloneDXDependency{Ref: indexRef}
+	seen := map[string]bool{}
+
+	for _, col := range r.Columns {
+		bundleRef := fmt.Sprintf("bundle:%s", col.OperatorBundleName)
+		bom.Components = append(bom.Components, cycloneDXComponent{
+			BOMRef:  bundleRef,
+			Type:    "application",
+			Name:    col.OperatorBundleName,
+			Version: col.OperatorBundleVersion,
+			PURL:    purlFor(col.BundlePath, ""),
+		})
+		indexDeps.DependsOn = append(indexDeps.DependsOn, bundleRef)
+
+		bundleDeps := cycloneDXDependency{Ref: bundleRef}
+		if col.BundleCSV != nil {
+			for _, related := range col.BundleCSV.Spec.RelatedImages {
+				bundleDeps.DependsOn = append(bundleDeps.DependsOn,
+					addImageComponents(&bom, seen, related.Image)...)
+			}
+			if col.BundleCSV.Spec.InstallStrategy.StrategySpec.DeploymentSpecs != nil {
+				for _, d := range col.BundleCSV.Spec.InstallStrategy.StrategySpec.DeploymentSpecs {
+					for _, c := range d.Spec.Template.Spec.Containers {
+						bundleDeps.DependsOn = append(bundleDeps.DependsOn,
+							addImageComponents(&bom, seen, c.Image)...)
+					}
+				}
+			}
+		}
+		bom.Dependencies = append(bom.Dependencies, bundleDeps)
+	}
+
+	bom.Dependencies = append([]cycloneDXDependency{indexDeps}, bom.Dependencies...)
+
+	data, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal CycloneDX SBOM : %s", err)
+	}
+
+	path := fmt.Sprintf("%s/cyclonedx-sbom.json", r.Flags.OutputPath)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("unable to write CycloneDX SBOM to (%s) : %s", path, err)
+	}
+
+	log.Infof("CycloneDX SBOM written to %s", path)
+	return nil
+}
+
+// addImageComponents resolves imageRef's platform variants via the shared
+// manifest resolver, adds one component per variant to bom and returns
+// their bom-refs so the caller can wire up the dependency graph. An image
+// shared across bundles (e.g. the same operand in RelatedImages and an
+// install image) is only added to bom.Components once, per the CycloneDX
+// bom-ref uniqueness constraint, but its refs are still returned so every
+// dependent bundle can reference it.
+func addImageComponents(bom *cycloneDXBOM, seen map[string]bool, imageRef string) []string {
+	info, err := manifest.DefaultResolver.Resolve(imageRef)
+	if err != nil {
+		log.Errorf("unable to resolve manifest for image (%s) while building SBOM : %s", imageRef, err)
+		return nil
+	}
+
+	var refs []string
+	for _, platform := range info.Platforms {
+		ref := fmt.Sprintf("image:%s@%s", imageRef, platform.Digest)
+		refs = append(refs, ref)
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		bom.Components = append(bom.Components, cycloneDXComponent{
+			BOMRef: ref,
+			Type:   "container",
+			Name:   imageRef,
+			PURL:   purlFor(imageRef, platform.Digest),
+			Properties: []cycloneDXProperty{
+				{Name: "operatorframework.io/arch." + platform.Architecture, Value: "supported"},
+				{Name: "os", Value: platform.OS},
+			},
+		})
+	}
+	return refs
+}
+
+// purlFor builds a spec-conformant OCI Package URL for imageRef:
+// pkg:oci/<name>@<digest>?repository_url=<registry>/<repository>, per
+// https://github.com/package-url/purl-spec/blob/master/PURL-TYPES.rst#oci.
+// digest is optional (the index and bundle components aren't content-
+// addressed, only the platform-specific image components are); when
+// imageRef doesn't parse as an image reference, it falls back to a
+// best-effort purl rather than failing the whole report.
+func purlFor(imageRef, digest string) string {
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		log.Errorf("unable to parse image reference (%s) while building purl : %s", imageRef, err)
+		return fmt.Sprintf("pkg:oci/%s", imageRef)
+	}
+
+	repo := ref.Context()
+	nameParts := strings.Split(repo.RepositoryStr(), "/")
+	purl := fmt.Sprintf("pkg:oci/%s", nameParts[len(nameParts)-1])
+	if digest != "" {
+		purl += "@" + digest
+	}
+
+	qualifiers := url.Values{"repository_url": {repo.RegistryStr() + "/" + repo.RepositoryStr()}}
+	return purl + "?" + qualifiers.Encode()
+}
@@ -140,6 +140,10 @@ func (d *Data) OutputReport() error {
 		if err := report.writeJSON(); err != nil {
 			return err
 		}
+	case pkg.CycloneDX:
+		if err := report.writeCycloneDX(); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("invalid output format : %s", d.Flags.OutputFormat)
 	}
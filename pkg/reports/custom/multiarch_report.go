@@ -1,7 +1,7 @@
 // Copyright 2021 The Audit Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
-// you may not use this File except in compliance with the License.
+// you may not use this file except in compliance with the License.
 // You may obtain a copy of the License at
 //
 //     http://www.apache.org/licenses/LICENSE-2.0
@@ -12,14 +12,24 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// Package custom builds the multi-arch audit report. It reads
+// bundlesReport.Flags.{Concurrency, SupportMatrix, Scanner, Severity,
+// OfflineDB, CheckArtifacts, EnableValidators, DisableValidators}; these
+// are added to pkg.Flags and bound by BindFlags alongside the existing
+// index/output flags, the same way CheckArtifacts was introduced for OCI
+// referrers lookups.
 package custom
 
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/operator-framework/audit/pkg"
+	"github.com/operator-framework/audit/pkg/manifest"
 	"github.com/operator-framework/audit/pkg/reports/bundles"
+	"github.com/operator-framework/audit/pkg/scan"
+	"github.com/operator-framework/audit/pkg/support"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -33,11 +43,42 @@ type MultiArchBundle struct {
 	// Images versus manifest arch
 	RelateImages map[string][]string
 	// Images versus manifest arch
-	InstallImages      map[string][]string
-	BundleData         bundles.Column
-	Validations        []string
-	Supported          map[string]string
-	HasMultArchSupport bool
+	InstallImages map[string][]string
+	// RelateImagePlatforms and InstallImagePlatforms mirror RelateImages
+	// and InstallImages but keep the full platform tuple (including
+	// Variant), which a plain "os.arch" string would lose.
+	RelateImagePlatforms  map[string][]manifest.Platform
+	InstallImagePlatforms map[string][]manifest.Platform
+	BundleData            bundles.Column
+	Validations           []string
+	Supported             map[string]string
+	// DeclaredPlatforms is the support.Matrix requirement for this
+	// bundle's package when --support-matrix was loaded; otherwise it is
+	// one linux platform per arch in Supported (see checkSupport).
+	DeclaredPlatforms []support.Platform
+	// SignedImages, ImagesWithSBOM and ImagesWithProvenance are only
+	// populated when bundlesReport.Flags.CheckArtifacts is set, since the
+	// referrers lookup is an extra registry round trip per image. They are
+	// counted out of VerifiedImages, not TotalImages: an image whose
+	// referrers lookup itself failed couldn't be confirmed signed/SBOM'd
+	// either way, so it must not be reported as confirmed-missing.
+	SignedImages         int
+	ImagesWithSBOM       int
+	ImagesWithProvenance int
+	VerifiedImages       int
+	TotalImages          int
+	HasMultArchSupport   bool
+	// CVECountBySeverity and FixedAvailableCVEs are only populated when
+	// bundlesReport.Flags.Scanner is set, since scanning every image is
+	// comparatively expensive.
+	CVECountBySeverity map[scan.Severity]int
+	FixedAvailableCVEs []string
+	// CriticalFixableCVEs are the IDs of CVEs that are both Critical
+	// severity and have a fix already available, i.e. the intersection
+	// audit0006-critical-cve-fix-available flags on, as opposed to
+	// FixedAvailableCVEs/CVECountBySeverity[Critical] which tally each
+	// independently and aren't safe to combine.
+	CriticalFixableCVEs []string
 }
 
 type MultiArchPkg struct {
@@ -62,30 +103,90 @@ func NewMultiArchReport(bundlesReport bundles.Report, filter string) *MultiArchR
 	multiArch.ImageBuild = bundlesReport.IndexImageInspect.Created
 	multiArch.GeneratedAt = bundlesReport.GenerateAt
 
+	manifest.Configure(bundlesReport.Flags.Concurrency)
+
+	var matrix *support.Matrix
+	if bundlesReport.Flags.SupportMatrix != "" {
+		var err error
+		matrix, err = support.Load(bundlesReport.Flags.SupportMatrix)
+		if err != nil {
+			log.Errorf("unable to load support matrix : %s", err)
+		}
+	}
+
+	var scanPool *scan.Pool
+	if bundlesReport.Flags.Scanner != "" {
+		scanner, err := scan.New(bundlesReport.Flags.Scanner, bundlesReport.Flags.Severity, bundlesReport.Flags.OfflineDB)
+		if err != nil {
+			log.Errorf("unable to configure vulnerability scanner : %s", err)
+		} else {
+			scanPool = scan.NewPool(scanner, bundlesReport.Flags.Concurrency)
+		}
+	}
+
 	mapPerPkgHeadsOnly := mapHeadBundlesPerPackageWith(bundlesReport.Columns)
-	mapPackagesWithMultData := make(map[string][]MultiArchBundle)
 
-	for pkg, bundles := range mapPerPkgHeadsOnly {
-		for _, bundle := range bundles {
+	type pkgBundle struct {
+		pkg    string
+		bundle bundles.Column
+	}
+	var work []pkgBundle
+	for pkg, bundleList := range mapPerPkgHeadsOnly {
+		for _, bundle := range bundleList {
 			// filter by the name
-			if len(filter) > 0 {
-				if !strings.Contains(bundle.PackageName, filter) {
-					continue
-				}
+			if len(filter) > 0 && !strings.Contains(bundle.PackageName, filter) {
+				continue
 			}
-			mb := MultiArchBundle{BundleData: bundle}
+			work = append(work, pkgBundle{pkg: pkg, bundle: bundle})
+		}
+	}
+
+	// Each bundle's data gathering is dominated by registry round trips
+	// (manifest/referrers lookups, scans), so bundles are fanned out across
+	// goroutines bounded by the same --concurrency used to size the
+	// manifest resolver and scan pool, instead of visiting them one at a
+	// time.
+	concurrency := bundlesReport.Flags.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	mapPackagesWithMultData := make(map[string][]MultiArchBundle)
+
+	for _, w := range work {
+		w := w
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			mb := MultiArchBundle{BundleData: w.bundle}
 			mb.addInfraLabels()
 			mb.addDisconnectAnnotationValue()
 			mb.addDataFromInstallImages(bundlesReport)
 			mb.addDataFromRelateImages(bundlesReport)
-			mb.checkSupport()
+			if bundlesReport.Flags.CheckArtifacts {
+				mb.addDataFromArtifacts()
+			}
+			if scanPool != nil {
+				mb.addDataFromScan(scanPool)
+			}
+			mb.checkSupport(matrix)
 			if len(mb.Supported) > 1 {
 				mb.HasMultArchSupport = true
 			}
-			mb.validate()
-			mapPackagesWithMultData[pkg] = append(mapPackagesWithMultData[pkg], mb)
-		}
+			mb.Validations = append(mb.Validations,
+				RunValidators(&mb, bundlesReport.Flags.EnableValidators, bundlesReport.Flags.DisableValidators)...)
+
+			mu.Lock()
+			mapPackagesWithMultData[w.pkg] = append(mapPackagesWithMultData[w.pkg], mb)
+			mu.Unlock()
+		}()
 	}
+	wg.Wait()
 
 	for pkg, bundles := range mapPackagesWithMultData {
 		multiArch.Packages = append(multiArch.Packages, MultiArchPkg{Name: pkg, Bundles: bundles})
@@ -110,22 +211,20 @@ func (mb *MultiArchBundle) addDisconnectAnnotationValue() {
 
 func (mb *MultiArchBundle) addDataFromInstallImages(bundlesReport bundles.Report) {
 	mb.InstallImages = make(map[string][]string)
+	mb.InstallImagePlatforms = make(map[string][]manifest.Platform)
 	if mb.BundleData.BundleCSV.Spec.InstallStrategy.StrategySpec.DeploymentSpecs != nil {
 		for _, v := range mb.BundleData.BundleCSV.Spec.InstallStrategy.StrategySpec.DeploymentSpecs {
 			for _, c := range v.Spec.Template.Spec.Containers {
-				manifest, err := pkg.RunDockerManifestInspect(c.Image, bundlesReport.Flags.ContainerEngine)
+				info, err := manifest.DefaultResolver.Resolve(c.Image)
 				if err != nil {
-					// Try again
-					if manifest, err = pkg.RunDockerManifestInspect(c.Image, bundlesReport.Flags.ContainerEngine); err != nil {
-						mb.BundleData.AuditErrors = append(mb.BundleData.AuditErrors, err.Error())
-						log.Errorf("unable to inspect manifests for the container image (%s) : %s", c.Image, err)
-						continue
-					}
+					mb.BundleData.AuditErrors = append(mb.BundleData.AuditErrors, err.Error())
+					log.Errorf("unable to inspect manifests for the container image (%s) : %s", c.Image, err)
+					continue
 				}
-				for _, manifest := range manifest.ManifestData {
+				for _, platform := range info.Platforms {
 					mb.InstallImages[c.Image] = append(mb.InstallImages[c.Image],
-						fmt.Sprintf("%s.%s", manifest.Platform.SO,
-							manifest.Platform.Architecture))
+						fmt.Sprintf("%s.%s", platform.OS, platform.Architecture))
+					mb.InstallImagePlatforms[c.Image] = append(mb.InstallImagePlatforms[c.Image], platform)
 				}
 			}
 		}
@@ -134,124 +233,132 @@ func (mb *MultiArchBundle) addDataFromInstallImages(bundlesReport bundles.Report
 
 func (mb *MultiArchBundle) addDataFromRelateImages(bundlesReport bundles.Report) {
 	mb.RelateImages = make(map[string][]string)
+	mb.RelateImagePlatforms = make(map[string][]manifest.Platform)
 	for _, v := range mb.BundleData.BundleCSV.Spec.RelatedImages {
-		manifest, err := pkg.RunDockerManifestInspect(v.Image, bundlesReport.Flags.ContainerEngine)
+		info, err := manifest.DefaultResolver.Resolve(v.Image)
 		if err != nil {
-			// Try again
-			if manifest, err = pkg.RunDockerManifestInspect(v.Image, bundlesReport.Flags.ContainerEngine); err != nil {
-				mb.BundleData.AuditErrors = append(mb.BundleData.AuditErrors, err.Error())
-				msg := fmt.Sprintf("unable to inspect manifests for the image (%s) : %s", v.Image, err)
-				log.Errorf(msg)
-				mb.Validations = append(mb.Validations, msg)
-				continue
-			}
+			mb.BundleData.AuditErrors = append(mb.BundleData.AuditErrors, err.Error())
+			msg := fmt.Sprintf("unable to inspect manifests for the image (%s) : %s", v.Image, err)
+			log.Errorf(msg)
+			mb.Validations = append(mb.Validations, msg)
+			continue
 		}
-		if manifest.ManifestData != nil {
-			for _, manifest := range manifest.ManifestData {
-				mb.RelateImages[v.Image] = append(mb.RelateImages[v.Image],
-					fmt.Sprintf("%s.%s", manifest.Platform.SO,
-						manifest.Platform.Architecture))
-			}
+		for _, platform := range info.Platforms {
+			mb.RelateImages[v.Image] = append(mb.RelateImages[v.Image],
+				fmt.Sprintf("%s.%s", platform.OS, platform.Architecture))
+			mb.RelateImagePlatforms[v.Image] = append(mb.RelateImagePlatforms[v.Image], platform)
 		}
 	}
 }
 
-func (mb *MultiArchBundle) validate() {
-	mb.checkSHA()
-	mb.checkLabels()
-	mb.checkAnnotation()
-	mb.checkMissingArchtype()
-}
-
-// check if any image is missing some archetype
-func (mb *MultiArchBundle) checkMissingArchtype() {
-	if mb.HasMultArchSupport {
-		for image, arc := range mb.RelateImages {
-			for su := range mb.Supported {
-				found := false
-				for _, t := range arc {
-					if strings.Contains(t, su) {
-						found = true
-						break
-					}
-				}
-				if !found {
-					mb.Validations = append(mb.Validations,
-						fmt.Errorf("[bundle %s]: related image (%s) is missing manifest archetype for %s",
-							mb.BundleData.BundleCSV.Name, image, su).Error())
-				}
-			}
+// installAndRelatedImages returns the de-duplicated set of this bundle's
+// install and related images, so an image listed in both (or repeated
+// across related images) is only counted/queried once.
+func (mb *MultiArchBundle) installAndRelatedImages() []string {
+	seen := make(map[string]bool, len(mb.InstallImages)+len(mb.RelateImages))
+	images := make([]string, 0, len(mb.InstallImages)+len(mb.RelateImages))
+	for image := range mb.InstallImages {
+		if !seen[image] {
+			seen[image] = true
+			images = append(images, image)
 		}
-
-		for image, arc := range mb.InstallImages {
-			for su := range mb.Supported {
-				found := false
-				for _, t := range arc {
-					if strings.Contains(t, su) {
-						found = true
-						break
-					}
-				}
-				if !found {
-					mb.Validations = append(mb.Validations,
-						fmt.Errorf("[bundle %s]: install image (%s) is missing manifest archetype for %s",
-							mb.BundleData.BundleCSV.Name, image, su).Error())
-				}
-			}
+	}
+	for image := range mb.RelateImages {
+		if !seen[image] {
+			seen[image] = true
+			images = append(images, image)
 		}
 	}
+	return images
 }
 
-// check if all images are using SHA
-func (mb *MultiArchBundle) checkSHA() {
-	if mb.HasMultArchSupport {
-		for image := range mb.RelateImages {
-			if !strings.Contains(image, "@sha256") {
-				mb.Validations = append(mb.Validations,
-					fmt.Errorf("[bundle %s]: releated image (%s) is not set using SHA",
-						mb.BundleData.BundleCSV.Name, image).Error())
-			}
-		}
+// addDataFromArtifacts queries the registry for OCI referrers (signatures,
+// SBOMs and SLSA provenance attestations) of every install and related
+// image and tallies how many of each were found.
+func (mb *MultiArchBundle) addDataFromArtifacts() {
+	images := mb.installAndRelatedImages()
+	mb.TotalImages = len(images)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, image := range images {
+		image := image
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			artifacts, err := manifest.DefaultResolver.FetchArtifacts(image)
 
-		for image := range mb.InstallImages {
-			if !strings.Contains(image, "@sha256") {
-				mb.Validations = append(mb.Validations,
-					fmt.Errorf("[bundle %s]: install image (%s) is not set using SHA",
-						mb.BundleData.BundleCSV.Name, image).Error())
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				msg := fmt.Sprintf("unable to inspect referrers for the image (%s) : %s", image, err)
+				log.Errorf(msg)
+				mb.Validations = append(mb.Validations, msg)
+				return
 			}
-		}
+			mb.VerifiedImages++
+			if artifacts.Signed {
+				mb.SignedImages++
+			}
+			if artifacts.SBOM {
+				mb.ImagesWithSBOM++
+			}
+			if artifacts.Provenance {
+				mb.ImagesWithProvenance++
+			}
+		}()
 	}
+	wg.Wait()
 }
 
-func (mb *MultiArchBundle) checkAnnotation() {
-	if mb.HasMultArchSupport && !mb.HasDisconnectAnnotation {
-		mb.Validations = append(mb.Validations,
-			fmt.Errorf("found multiacrh support for the bundle (%q), however "+
-				"it is missing the CSV disconnected annotation", mb.BundleData.BundleCSV.Name).Error())
-	}
-}
+// addDataFromScan scans every install and related image via pool, keyed
+// by the image's resolved digest so that the same image across bundles
+// only pays the scan cost once, and tallies CVE counts by severity.
+func (mb *MultiArchBundle) addDataFromScan(pool *scan.Pool) {
+	mb.CVECountBySeverity = make(map[scan.Severity]int)
 
-func (mb *MultiArchBundle) checkLabels() {
-	notFoundLabel := []string{}
-	if mb.HasMultArchSupport {
-		for supported := range mb.Supported {
-			found := false
-			for _, infra := range mb.InfraLabels {
-				if strings.Contains(infra, supported) {
-					found = true
-					break
-				}
+	images := mb.installAndRelatedImages()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, image := range images {
+		image := image
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			info, err := manifest.DefaultResolver.Resolve(image)
+			if err != nil {
+				log.Errorf("unable to resolve digest to scan image (%s) : %s", image, err)
+				return
 			}
-			if !found {
-				notFoundLabel = append(notFoundLabel, supported)
+
+			result, err := pool.Scan(image, info.Digest)
+			if err != nil {
+				msg := fmt.Sprintf("unable to scan image (%s) : %s", image, err)
+				log.Errorf(msg)
+				mu.Lock()
+				mb.Validations = append(mb.Validations, msg)
+				mu.Unlock()
+				return
 			}
-		}
 
-		if len(notFoundLabel) > 0 {
-			mb.Validations = append(mb.Validations,
-				fmt.Errorf("[bundle %s]: missing label for %q", mb.BundleData.BundleCSV.Name, notFoundLabel).Error())
-		}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, cve := range result.CVEs {
+				mb.CVECountBySeverity[cve.Severity]++
+				if !cve.FixAvailable {
+					continue
+				}
+				mb.FixedAvailableCVEs = append(mb.FixedAvailableCVEs, cve.ID)
+				if cve.Severity == scan.SeverityCritical {
+					mb.CriticalFixableCVEs = append(mb.CriticalFixableCVEs, cve.ID)
+				}
+			}
+		}()
 	}
+	wg.Wait()
 }
 
 // MapBundlesPerPackage returns map with all bundles found per pkg name
@@ -265,7 +372,7 @@ func mapHeadBundlesPerPackageWith(bundlesReport []bundles.Column) map[string][]b
 	return mapPackagesWithBundles
 }
 
-func (mb *MultiArchBundle) checkSupport() {
+func (mb *MultiArchBundle) checkSupport(matrix *support.Matrix) {
 	if mb.Supported == nil {
 		mb.Supported = make(map[string]string)
 	}
@@ -297,4 +404,36 @@ func (mb *MultiArchBundle) checkSupport() {
 			}
 		}
 	}
+
+	// Auto-select the platforms this bundle is required to support. When a
+	// matrix was loaded, that is simply its declared requirement for this
+	// package — the *full* set, not just the platforms already confirmed
+	// by the manifests, since it's precisely the unconfirmed ones that
+	// missingArchtypeValidator (audit0004) needs to flag. With no matrix at
+	// all, fall back to the pre-matrix behavior: check every image
+	// against every arch this bundle's own labels/manifests claim (i.e.
+	// mb.Supported), so existing users without --support-matrix keep the
+	// same completeness check they had before.
+	if matrix != nil {
+		mb.DeclaredPlatforms = matrix.RequiredFor(mb.BundleData.PackageName)
+		return
+	}
+
+	mb.DeclaredPlatforms = make([]support.Platform, 0, len(mb.Supported))
+	for arch := range mb.Supported {
+		mb.DeclaredPlatforms = append(mb.DeclaredPlatforms, support.Platform{OS: "linux", Arch: arch})
+	}
+}
+
+func platformSliceHas(platforms []manifest.Platform, p support.Platform) bool {
+	for _, m := range platforms {
+		if m.OS != p.OS || m.Architecture != p.Arch {
+			continue
+		}
+		if p.Variant != "" && m.Variant != p.Variant {
+			continue
+		}
+		return true
+	}
+	return false
 }
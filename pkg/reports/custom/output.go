@@ -0,0 +1,130 @@
+// Copyright 2021 The Audit Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custom
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/operator-framework/audit/pkg"
+	"github.com/operator-framework/audit/pkg/reports/bundles"
+	"github.com/operator-framework/audit/pkg/scan"
+	log "github.com/sirupsen/logrus"
+	"github.com/xuri/excelize/v2"
+)
+
+// OutputReport writes the report in bundlesReport.Flags.OutputFormat to
+// bundlesReport.Flags.OutputPath, mirroring bundles.Data.OutputReport's
+// dispatch.
+func (r *MultiArchReport) OutputReport(bundlesReport bundles.Report) error {
+	switch bundlesReport.Flags.OutputFormat {
+	case pkg.Xls:
+		return r.writeXls(bundlesReport.Flags.OutputPath)
+	case pkg.JSON:
+		return r.WriteJSON(bundlesReport.Flags.OutputPath)
+	default:
+		return fmt.Errorf("invalid output format : %s", bundlesReport.Flags.OutputFormat)
+	}
+}
+
+// WriteJSON marshals the report, including every MultiArchBundle's
+// artifact (SignedImages, ImagesWithSBOM, ImagesWithProvenance) and CVE
+// (CVECountBySeverity, FixedAvailableCVEs) data, to
+// outputPath/multiarch-report.json.
+func (r *MultiArchReport) WriteJSON(outputPath string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal multi-arch report : %s", err)
+	}
+
+	path := fmt.Sprintf("%s/multiarch-report.json", outputPath)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("unable to write multi-arch report to (%s) : %s", path, err)
+	}
+
+	log.Infof("multi-arch report written to %s", path)
+	return nil
+}
+
+// writeXls renders the report as a single-sheet spreadsheet, one row per
+// MultiArchBundle, to outputPath/multiarch-report.xlsx.
+func (r *MultiArchReport) writeXls(outputPath string) error {
+	f := excelize.NewFile()
+	sheet := "MultiArch"
+	if err := f.SetSheetName(f.GetSheetName(0), sheet); err != nil {
+		return fmt.Errorf("unable to name multi-arch report sheet : %s", err)
+	}
+
+	if err := writeXlsRow(f, sheet, 1, xlsHeader()); err != nil {
+		return err
+	}
+
+	row := 2
+	for _, p := range r.Packages {
+		for _, b := range p.Bundles {
+			if err := writeXlsRow(f, sheet, row, xlsRowFor(p.Name, b)); err != nil {
+				return err
+			}
+			row++
+		}
+	}
+
+	path := fmt.Sprintf("%s/multiarch-report.xlsx", outputPath)
+	if err := f.SaveAs(path); err != nil {
+		return fmt.Errorf("unable to write multi-arch report to (%s) : %s", path, err)
+	}
+
+	log.Infof("multi-arch report written to %s", path)
+	return nil
+}
+
+// xlsHeader returns the column titles for writeXls, in the same order as
+// xlsRowFor.
+func xlsHeader() []interface{} {
+	return []interface{}{
+		"Package", "Bundle", "Has Multi-Arch Support",
+		"Signed Images", "Images With SBOM", "Images With Provenance", "Verified Images", "Total Images",
+		"Critical CVEs", "High CVEs", "Medium CVEs", "Low CVEs", "Fixed Available CVEs", "Critical Fixable CVEs",
+	}
+}
+
+// xlsRowFor returns pkgName and mb's artifact and CVE columns, in the
+// same order as xlsHeader.
+func xlsRowFor(pkgName string, mb MultiArchBundle) []interface{} {
+	return []interface{}{
+		pkgName, mb.BundleData.BundleCSV.Name, mb.HasMultArchSupport,
+		mb.SignedImages, mb.ImagesWithSBOM, mb.ImagesWithProvenance, mb.VerifiedImages, mb.TotalImages,
+		mb.CVECountBySeverity[scan.SeverityCritical], mb.CVECountBySeverity[scan.SeverityHigh],
+		mb.CVECountBySeverity[scan.SeverityMedium], mb.CVECountBySeverity[scan.SeverityLow],
+		strings.Join(mb.FixedAvailableCVEs, ", "), strings.Join(mb.CriticalFixableCVEs, ", "),
+	}
+}
+
+// writeXlsRow sets values starting at column A of the given 1-indexed
+// row.
+func writeXlsRow(f *excelize.File, sheet string, row int, values []interface{}) error {
+	for i, v := range values {
+		cell, err := excelize.CoordinatesToCellName(i+1, row)
+		if err != nil {
+			return fmt.Errorf("unable to compute cell for row %d : %s", row, err)
+		}
+		if err := f.SetCellValue(sheet, cell, v); err != nil {
+			return fmt.Errorf("unable to set cell (%s) : %s", cell, err)
+		}
+	}
+	return nil
+}
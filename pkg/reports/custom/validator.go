@@ -0,0 +1,139 @@
+// Copyright 2021 The Audit Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custom
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Severity classifies how serious a Result is.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Result is a single finding produced by a Validator for one bundle.
+type Result struct {
+	ValidatorID string
+	Severity    Severity
+	Message     string
+}
+
+func (r Result) String() string {
+	return r.Message
+}
+
+// Validator is one addressable check a CustomReport runs against a
+// bundle, in the same spirit as the amNNNN numbered validators used by
+// addon-metadata-operator: a stable ID operator authors can reference to
+// suppress or waive in CI, a human description, a severity, and the
+// check itself.
+//
+// Run receives the already-resolved MultiArchBundle (manifests, infra
+// labels, declared support matrix, ...) rather than a raw bundle, since
+// that data is expensive to gather and is shared across every validator
+// for a given bundle.
+type Validator interface {
+	ID() string
+	Description() string
+	Severity() Severity
+	Run(mb *MultiArchBundle) []Result
+}
+
+// registry holds every validator registered via Register; order keeps
+// their registration order. The validators a report runs are ordered by ID
+// (see selected), so built-ins and out-of-tree additions both get a
+// deterministic, stable report ordering.
+var registry = map[string]Validator{}
+var order []string
+
+// Register adds v to the set of validators every CustomReport can run.
+// Built-in validators call this from an init() in this package;
+// out-of-tree validators do the same from their own init(), as long as
+// their package is imported (blank-imported, if only for the side
+// effect) by the audit binary.
+func Register(v Validator) {
+	if _, exists := registry[v.ID()]; exists {
+		panic(fmt.Sprintf("validator %q is already registered", v.ID()))
+	}
+	registry[v.ID()] = v
+	order = append(order, v.ID())
+}
+
+// Validators returns every registered validator, in registration order.
+func Validators() []Validator {
+	all := make([]Validator, 0, len(order))
+	for _, id := range order {
+		all = append(all, registry[id])
+	}
+	return all
+}
+
+// selected resolves which validator IDs should run: every registered
+// validator by default, minus disable, or only enable when it is
+// non-empty. An unknown ID in either list is ignored rather than
+// treated as an error, since registries grow over time.
+func selected(enable, disable []string) []Validator {
+	enabledSet := toSet(enable)
+	disabledSet := toSet(disable)
+
+	var result []Validator
+	for _, v := range Validators() {
+		if len(enabledSet) > 0 && !enabledSet[v.ID()] {
+			continue
+		}
+		if disabledSet[v.ID()] {
+			continue
+		}
+		result = append(result, v)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID() < result[j].ID() })
+	return result
+}
+
+func toSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// RunValidators runs every selected validator against mb and returns
+// their findings as the plain-string messages MultiArchBundle.Validations
+// has always stored.
+func RunValidators(mb *MultiArchBundle, enable, disable []string) []string {
+	var messages []string
+	for _, v := range selected(enable, disable) {
+		for _, res := range v.Run(mb) {
+			messages = append(messages, res.Message)
+		}
+	}
+	return messages
+}
+
+// CustomReport is implemented by every report built on this package's
+// validator registry (today, only MultiArchReport). It only exposes the
+// report's name; validators are selected and run through RunValidators,
+// not through this interface.
+type CustomReport interface {
+	// Name identifies the report for log messages, e.g. "multiarch".
+	Name() string
+}
+
+func (r *MultiArchReport) Name() string { return "multiarch" }
@@ -0,0 +1,214 @@
+// Copyright 2021 The Audit Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package custom
+
+import (
+	"fmt"
+	"strings"
+)
+
+func init() {
+	Register(imagesSHAValidator{})
+	Register(archLabelsValidator{})
+	Register(disconnectedAnnotationValidator{})
+	Register(missingArchtypeValidator{})
+	Register(unsignedImagesValidator{})
+	Register(criticalCVEWithFixValidator{})
+}
+
+// imagesSHAValidator requires every install/related image of a
+// multi-arch bundle to be pinned by digest rather than by tag.
+type imagesSHAValidator struct{}
+
+func (imagesSHAValidator) ID() string         { return "audit0001-images-sha" }
+func (imagesSHAValidator) Severity() Severity { return SeverityError }
+func (imagesSHAValidator) Description() string {
+	return "install and related images of a multi-arch bundle must be referenced by @sha256 digest"
+}
+
+func (v imagesSHAValidator) Run(mb *MultiArchBundle) []Result {
+	if !mb.HasMultArchSupport {
+		return nil
+	}
+
+	var results []Result
+	for image := range mb.RelateImages {
+		if !strings.Contains(image, "@sha256") {
+			results = append(results, Result{ValidatorID: v.ID(), Severity: v.Severity(),
+				Message: fmt.Sprintf("[bundle %s]: releated image (%s) is not set using SHA",
+					mb.BundleData.BundleCSV.Name, image)})
+		}
+	}
+	for image := range mb.InstallImages {
+		if !strings.Contains(image, "@sha256") {
+			results = append(results, Result{ValidatorID: v.ID(), Severity: v.Severity(),
+				Message: fmt.Sprintf("[bundle %s]: install image (%s) is not set using SHA",
+					mb.BundleData.BundleCSV.Name, image)})
+		}
+	}
+	return results
+}
+
+// archLabelsValidator requires a CSV label for every architecture the
+// bundle's images declare support for.
+type archLabelsValidator struct{}
+
+func (archLabelsValidator) ID() string         { return "audit0002-arch-labels" }
+func (archLabelsValidator) Severity() Severity { return SeverityWarning }
+func (archLabelsValidator) Description() string {
+	return "a multi-arch bundle's CSV must carry an operatorframework.io/arch.* label for each supported arch"
+}
+
+func (v archLabelsValidator) Run(mb *MultiArchBundle) []Result {
+	if !mb.HasMultArchSupport {
+		return nil
+	}
+
+	var notFoundLabel []string
+	for supported := range mb.Supported {
+		found := false
+		for _, infra := range mb.InfraLabels {
+			if strings.Contains(infra, supported) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			notFoundLabel = append(notFoundLabel, supported)
+		}
+	}
+
+	if len(notFoundLabel) == 0 {
+		return nil
+	}
+	return []Result{{ValidatorID: v.ID(), Severity: v.Severity(),
+		Message: fmt.Sprintf("[bundle %s]: missing label for %q", mb.BundleData.BundleCSV.Name, notFoundLabel)}}
+}
+
+// disconnectedAnnotationValidator requires the CSV disconnected/air-gapped
+// infrastructure annotation on any bundle claiming multi-arch support.
+type disconnectedAnnotationValidator struct{}
+
+func (disconnectedAnnotationValidator) ID() string         { return "audit0003-disconnected-annotation" }
+func (disconnectedAnnotationValidator) Severity() Severity { return SeverityWarning }
+func (disconnectedAnnotationValidator) Description() string {
+	return "a multi-arch bundle's CSV must carry the disconnected infrastructure annotation"
+}
+
+func (v disconnectedAnnotationValidator) Run(mb *MultiArchBundle) []Result {
+	if !mb.HasMultArchSupport || mb.HasDisconnectAnnotation {
+		return nil
+	}
+	return []Result{{ValidatorID: v.ID(), Severity: v.Severity(),
+		Message: fmt.Sprintf("found multiacrh support for the bundle (%q), however "+
+			"it is missing the CSV disconnected annotation", mb.BundleData.BundleCSV.Name)}}
+}
+
+// missingArchtypeValidator flags any install/related image whose manifest
+// does not advertise one of the bundle's declared platforms, variant
+// included.
+type missingArchtypeValidator struct{}
+
+func (missingArchtypeValidator) ID() string         { return "audit0004-missing-archtype" }
+func (missingArchtypeValidator) Severity() Severity { return SeverityError }
+func (missingArchtypeValidator) Description() string {
+	return "every install/related image of a multi-arch bundle must have a manifest entry for each declared platform"
+}
+
+func (v missingArchtypeValidator) Run(mb *MultiArchBundle) []Result {
+	if !mb.HasMultArchSupport {
+		return nil
+	}
+
+	var results []Result
+	for image, platforms := range mb.RelateImagePlatforms {
+		for _, declared := range mb.DeclaredPlatforms {
+			if !platformSliceHas(platforms, declared) {
+				results = append(results, Result{ValidatorID: v.ID(), Severity: v.Severity(),
+					Message: fmt.Sprintf("[bundle %s]: related image (%s) is missing manifest archetype for %s",
+						mb.BundleData.BundleCSV.Name, image, declared)})
+			}
+		}
+	}
+	for image, platforms := range mb.InstallImagePlatforms {
+		for _, declared := range mb.DeclaredPlatforms {
+			if !platformSliceHas(platforms, declared) {
+				results = append(results, Result{ValidatorID: v.ID(), Severity: v.Severity(),
+					Message: fmt.Sprintf("[bundle %s]: install image (%s) is missing manifest archetype for %s",
+						mb.BundleData.BundleCSV.Name, image, declared)})
+			}
+		}
+	}
+	return results
+}
+
+// unsignedImagesValidator requires every verifiable image of a multi-arch
+// bundle to carry a Cosign signature and an SBOM attachment. It only fires
+// when addDataFromArtifacts actually ran (VerifiedImages stays zero
+// otherwise), since the referrers lookup is opt-in. It compares against
+// VerifiedImages rather than TotalImages: an image whose referrers lookup
+// failed couldn't be confirmed either way, so it must not count toward
+// "unsigned"/"missing SBOM".
+type unsignedImagesValidator struct{}
+
+func (unsignedImagesValidator) ID() string         { return "audit0005-unsigned-images" }
+func (unsignedImagesValidator) Severity() Severity { return SeverityError }
+func (unsignedImagesValidator) Description() string {
+	return "a multi-arch/disconnected bundle must ship only signed, SBOM-attached images"
+}
+
+func (v unsignedImagesValidator) Run(mb *MultiArchBundle) []Result {
+	if !mb.HasMultArchSupport || mb.VerifiedImages == 0 {
+		return nil
+	}
+
+	var results []Result
+	if mb.SignedImages < mb.VerifiedImages {
+		results = append(results, Result{ValidatorID: v.ID(), Severity: v.Severity(),
+			Message: fmt.Sprintf("[bundle %s]: claims multi-arch/disconnected support but %d of %d verified images are unsigned",
+				mb.BundleData.BundleCSV.Name, mb.VerifiedImages-mb.SignedImages, mb.VerifiedImages)})
+	}
+	if mb.ImagesWithSBOM < mb.VerifiedImages {
+		results = append(results, Result{ValidatorID: v.ID(), Severity: v.Severity(),
+			Message: fmt.Sprintf("[bundle %s]: claims multi-arch/disconnected support but %d of %d verified images are missing an SBOM",
+				mb.BundleData.BundleCSV.Name, mb.VerifiedImages-mb.ImagesWithSBOM, mb.VerifiedImages)})
+	}
+	return results
+}
+
+// criticalCVEWithFixValidator fails multi-arch bundles that carry
+// Critical CVEs for which the scanner reports a fix is already
+// available. It only fires when addDataFromScan actually ran. It relies
+// on mb.CriticalFixableCVEs, which is already the Critical-and-fixed
+// intersection computed per CVE — CVECountBySeverity[Critical] and
+// FixedAvailableCVEs are independent tallies (any severity that's fixed,
+// any fix-state that's critical) and aren't safe to combine here.
+type criticalCVEWithFixValidator struct{}
+
+func (criticalCVEWithFixValidator) ID() string         { return "audit0006-critical-cve-fix-available" }
+func (criticalCVEWithFixValidator) Severity() Severity { return SeverityError }
+func (criticalCVEWithFixValidator) Description() string {
+	return "a multi-arch bundle must not ship images with Critical CVEs that already have a fix available"
+}
+
+func (v criticalCVEWithFixValidator) Run(mb *MultiArchBundle) []Result {
+	if !mb.HasMultArchSupport || len(mb.CriticalFixableCVEs) == 0 {
+		return nil
+	}
+	return []Result{{ValidatorID: v.ID(), Severity: v.Severity(),
+		Message: fmt.Sprintf("[bundle %s]: has %d Critical CVEs with a fix already available (%s)",
+			mb.BundleData.BundleCSV.Name, len(mb.CriticalFixableCVEs),
+			strings.Join(mb.CriticalFixableCVEs, ", "))}}
+}
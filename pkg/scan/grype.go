@@ -0,0 +1,82 @@
+// Copyright 2021 The Audit Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// grypeScanner shells out to the grype CLI and parses its JSON report.
+type grypeScanner struct {
+	severity  map[Severity]bool
+	offlineDB string
+}
+
+// NewGrypeScanner builds a Scanner backed by the grype CLI.
+func NewGrypeScanner(severity []string, offlineDB string) Scanner {
+	allowed := make(map[Severity]bool, len(severity))
+	for _, s := range severity {
+		allowed[normalizeSeverity(s)] = true
+	}
+	return &grypeScanner{severity: allowed, offlineDB: offlineDB}
+}
+
+func (g *grypeScanner) Name() string { return "grype" }
+
+type grypeReport struct {
+	Matches []struct {
+		Vulnerability struct {
+			ID       string `json:"id"`
+			Severity string `json:"severity"`
+			Fix      struct {
+				State string `json:"state"`
+			} `json:"fix"`
+		} `json:"vulnerability"`
+	} `json:"matches"`
+}
+
+func (g *grypeScanner) Scan(imageRef string) (*Result, error) {
+	cmd := exec.Command("grype", imageRef, "-o", "json")
+	if g.offlineDB != "" {
+		cmd.Env = append(os.Environ(), "GRYPE_DB_CACHE_DIR="+g.offlineDB, "GRYPE_DB_AUTO_UPDATE=false")
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to run grype against image (%s) : %s", imageRef, err)
+	}
+
+	var parsed grypeReport
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return nil, fmt.Errorf("unable to parse grype output for image (%s) : %s", imageRef, err)
+	}
+
+	result := &Result{ImageRef: imageRef}
+	for _, m := range parsed.Matches {
+		severity := normalizeSeverity(m.Vulnerability.Severity)
+		if len(g.severity) > 0 && !g.severity[severity] {
+			continue
+		}
+		result.CVEs = append(result.CVEs, CVE{
+			ID:           m.Vulnerability.ID,
+			Severity:     severity,
+			FixAvailable: m.Vulnerability.Fix.State == "fixed",
+		})
+	}
+	return result, nil
+}
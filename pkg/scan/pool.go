@@ -0,0 +1,94 @@
+// Copyright 2021 The Audit Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scan
+
+import "sync"
+
+// Pool runs a Scanner over many images concurrently, de-duplicating work
+// by image digest so that the same image shared across bundles is only
+// scanned once: the first caller for a digest runs the scan, every other caller
+// for that same digest (concurrent or not) waits on it and reuses its
+// result rather than invoking the scanner again.
+type Pool struct {
+	scanner Scanner
+
+	mu       sync.Mutex
+	results  map[string]*Result
+	errs     map[string]error
+	inFlight map[string]chan struct{}
+
+	sem chan struct{}
+}
+
+// NewPool builds a Pool that runs at most concurrency scans in flight at
+// once. A concurrency of zero or less defaults to one.
+func NewPool(scanner Scanner, concurrency int) *Pool {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Pool{
+		scanner:  scanner,
+		results:  make(map[string]*Result),
+		errs:     make(map[string]error),
+		inFlight: make(map[string]chan struct{}),
+		sem:      make(chan struct{}, concurrency),
+	}
+}
+
+// Scan scans imageRef, keyed by digest: if digest was already scanned
+// (directly or via another image reference resolving to the same
+// digest), the cached result is returned without invoking the scanner
+// again. If another goroutine is already scanning that digest, Scan
+// waits for it to finish instead of racing it.
+func (p *Pool) Scan(imageRef, digest string) (*Result, error) {
+	p.mu.Lock()
+	for {
+		if res, ok := p.results[digest]; ok {
+			p.mu.Unlock()
+			return res, nil
+		}
+		if err, ok := p.errs[digest]; ok {
+			p.mu.Unlock()
+			return nil, err
+		}
+		done, ok := p.inFlight[digest]
+		if !ok {
+			break
+		}
+		p.mu.Unlock()
+		<-done
+		p.mu.Lock()
+	}
+
+	done := make(chan struct{})
+	p.inFlight[digest] = done
+	p.mu.Unlock()
+
+	p.sem <- struct{}{}
+	res, err := p.scanner.Scan(imageRef)
+	<-p.sem
+
+	p.mu.Lock()
+	if err != nil {
+		p.errs[digest] = err
+	} else {
+		p.results[digest] = res
+	}
+	delete(p.inFlight, digest)
+	p.mu.Unlock()
+	close(done)
+
+	return res, err
+}
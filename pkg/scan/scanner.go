@@ -0,0 +1,93 @@
+// Copyright 2021 The Audit Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scan defines the pluggable vulnerability scanner interface used
+// by the reports to annotate bundle images with CVE data.
+package scan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity is a CVE severity bucket, matching the levels reported by both
+// Trivy and Grype.
+type Severity string
+
+const (
+	SeverityCritical Severity = "Critical"
+	SeverityHigh     Severity = "High"
+	SeverityMedium   Severity = "Medium"
+	SeverityLow      Severity = "Low"
+)
+
+// CVE is a single vulnerability finding, severity and fix-availability
+// attached so callers can correlate the two (e.g. "Critical CVEs that
+// already have a fix") instead of only seeing each as a separate,
+// disconnected tally.
+type CVE struct {
+	ID           string
+	Severity     Severity
+	FixAvailable bool
+}
+
+// Result is a single image's scan outcome.
+type Result struct {
+	ImageRef string
+	CVEs     []CVE
+}
+
+// Scanner scans a single image reference and returns its CVE findings.
+// Backends (trivy, grype) shell out to their respective CLI; out-of-tree
+// implementations can satisfy this interface to plug in another engine.
+type Scanner interface {
+	// Name identifies the backend, e.g. for log messages and the
+	// --scanner flag value that selected it.
+	Name() string
+	Scan(imageRef string) (*Result, error)
+}
+
+// normalizeSeverity maps a backend's raw severity string (Trivy emits
+// upper-case, e.g. "CRITICAL"; Grype emits title-case, e.g. "Critical") to
+// the shared Severity constants, so callers that group CVEs by severity and
+// the --severity filter behave the same regardless of which backend
+// produced the string. Values that don't match a known level pass through
+// unchanged.
+func normalizeSeverity(s string) Severity {
+	switch strings.ToLower(s) {
+	case "critical":
+		return SeverityCritical
+	case "high":
+		return SeverityHigh
+	case "medium":
+		return SeverityMedium
+	case "low":
+		return SeverityLow
+	default:
+		return Severity(s)
+	}
+}
+
+// New returns the Scanner registered for name, or an error if name does
+// not match a known backend.
+func New(name string, severity []string, offlineDB string) (Scanner, error) {
+	switch name {
+	case "trivy":
+		return NewTrivyScanner(severity, offlineDB), nil
+	case "grype":
+		return NewGrypeScanner(severity, offlineDB), nil
+	default:
+		return nil, fmt.Errorf("unknown scanner %q, expected \"trivy\" or \"grype\"", name)
+	}
+}
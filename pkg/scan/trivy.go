@@ -0,0 +1,82 @@
+// Copyright 2021 The Audit Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// trivyScanner shells out to the trivy CLI and parses its JSON report.
+type trivyScanner struct {
+	severity  string
+	offlineDB string
+}
+
+// NewTrivyScanner builds a Scanner backed by the trivy CLI.
+func NewTrivyScanner(severity []string, offlineDB string) Scanner {
+	upper := make([]string, len(severity))
+	for i, s := range severity {
+		upper[i] = strings.ToUpper(s)
+	}
+	return &trivyScanner{severity: strings.Join(upper, ","), offlineDB: offlineDB}
+}
+
+func (t *trivyScanner) Name() string { return "trivy" }
+
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID string `json:"VulnerabilityID"`
+			Severity        string `json:"Severity"`
+			FixedVersion    string `json:"FixedVersion"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+func (t *trivyScanner) Scan(imageRef string) (*Result, error) {
+	args := []string{"image", "--format", "json", "--quiet"}
+	if t.severity != "" {
+		args = append(args, "--severity", t.severity)
+	}
+	if t.offlineDB != "" {
+		args = append(args, "--skip-db-update", "--skip-java-db-update", "--cache-dir", t.offlineDB)
+	}
+	args = append(args, imageRef)
+
+	out, err := exec.Command("trivy", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to run trivy against image (%s) : %s", imageRef, err)
+	}
+
+	var parsed trivyReport
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return nil, fmt.Errorf("unable to parse trivy output for image (%s) : %s", imageRef, err)
+	}
+
+	result := &Result{ImageRef: imageRef}
+	for _, r := range parsed.Results {
+		for _, v := range r.Vulnerabilities {
+			result.CVEs = append(result.CVEs, CVE{
+				ID:           v.VulnerabilityID,
+				Severity:     normalizeSeverity(v.Severity),
+				FixAvailable: v.FixedVersion != "",
+			})
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 The Audit Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package support loads the user-declared platform support matrix used to
+// validate multi-arch bundles (--support-matrix matrix.yaml).
+package support
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Platform is a single (os, arch, variant) tuple a bundle is expected to
+// support, e.g. linux/arm64/v8. Variant is empty when the platform does
+// not distinguish one, e.g. linux/amd64.
+type Platform struct {
+	OS      string `yaml:"os"`
+	Arch    string `yaml:"arch"`
+	Variant string `yaml:"variant,omitempty"`
+}
+
+// String renders the platform the way users write it on the CLI/yaml,
+// e.g. "linux/arm64/v8" or "linux/amd64".
+func (p Platform) String() string {
+	if p.Variant == "" {
+		return fmt.Sprintf("%s/%s", p.OS, p.Arch)
+	}
+	return fmt.Sprintf("%s/%s/%s", p.OS, p.Arch, p.Variant)
+}
+
+// ParsePlatform parses a "os/arch[/variant]" string, e.g. "linux/arm64/v8".
+func ParsePlatform(s string) (Platform, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Platform{}, fmt.Errorf("invalid platform %q, expected os/arch or os/arch/variant", s)
+	}
+	p := Platform{OS: parts[0], Arch: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p, nil
+}
+
+// defaultPlatforms is the documented fallback that RequiredFor returns for
+// a nil Matrix, or for a package with neither a per-package override nor a
+// matrix-wide required list, mirroring how the docker CLI's LCOW
+// auto-select falls back to a default platform when nothing more specific
+// is declared.
+var defaultPlatforms = []Platform{
+	{OS: "linux", Arch: "amd64"},
+}
+
+// Matrix is the declared (os, arch, variant) support requirements, with
+// optional per-package overrides (e.g. a package that only ships
+// linux/amd64 even though the fleet default requires more).
+type Matrix struct {
+	Required []Platform            `yaml:"required"`
+	Packages map[string][]Platform `yaml:"packages,omitempty"`
+}
+
+// Load reads a support matrix from a yaml file such as:
+//
+//	required:
+//	  - os: linux
+//	    arch: amd64
+//	  - os: linux
+//	    arch: arm64
+//	    variant: v8
+//	packages:
+//	  my-operator:
+//	    - os: linux
+//	      arch: amd64
+func Load(path string) (*Matrix, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read support matrix (%s) : %s", path, err)
+	}
+
+	matrix := &Matrix{}
+	if err := yaml.Unmarshal(data, matrix); err != nil {
+		return nil, fmt.Errorf("unable to parse support matrix (%s) : %s", path, err)
+	}
+	return matrix, nil
+}
+
+// RequiredFor returns the declared platforms for packageName: its
+// per-package override when one exists, otherwise the matrix-wide
+// required list, and the documented default when neither is set.
+func (m *Matrix) RequiredFor(packageName string) []Platform {
+	if m == nil {
+		return defaultPlatforms
+	}
+	if override, ok := m.Packages[packageName]; ok && len(override) > 0 {
+		return override
+	}
+	if len(m.Required) > 0 {
+		return m.Required
+	}
+	return defaultPlatforms
+}